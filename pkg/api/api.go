@@ -0,0 +1,110 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Package api defines the wire-level contract shared by every ConTest API
+// transport (HTTP, JSON-RPC, ...) and their server-side listeners: the verbs
+// a client can issue, the data each of them returns, and how errors and
+// authentication are represented so that a caller can treat transports
+// interchangeably.
+package api
+
+import (
+	"github.com/linuxboot/contest/pkg/job"
+	"github.com/linuxboot/contest/pkg/types"
+	"github.com/linuxboot/contest/pkg/xcontext"
+)
+
+// Transport is implemented by every API client (pkg/transport/http,
+// pkg/transport/jsonrpc, ...). Each verb mirrors a ConTest server operation.
+type Transport interface {
+	Version(ctx xcontext.Context, requestor string) (*VersionResponse, error)
+	Start(ctx xcontext.Context, requestor string, jobDescriptor string) (*StartResponse, error)
+	Stop(ctx xcontext.Context, requestor string, jobID types.JobID) (*StopResponse, error)
+	Status(ctx xcontext.Context, requestor string, jobID types.JobID) (*StatusResponse, error)
+	Retry(ctx xcontext.Context, requestor string, jobID types.JobID) (*RetryResponse, error)
+	List(ctx xcontext.Context, requestor string, states []job.State, tags []string) (*ListResponse, error)
+}
+
+// Authenticator produces the value of the Authorization header to attach to
+// every API request. Implementations live in pkg/auth (Basic, Bearer,
+// OIDC); selecting one is a client-side option, e.g. http.HTTP{Auth: ...}.
+type Authenticator interface {
+	AuthHeader(ctx xcontext.Context) (string, error)
+}
+
+// ResponseDataVersion is the payload of a Version call.
+type ResponseDataVersion struct {
+	Version string `json:"version"`
+	Tag     string `json:"tag,omitempty"`
+}
+
+// ResponseDataStart is the payload of a Start call.
+type ResponseDataStart struct {
+	JobID types.JobID `json:"jobID"`
+}
+
+// ResponseDataStop is the payload of a Stop call.
+type ResponseDataStop struct {
+	JobID types.JobID `json:"jobID"`
+}
+
+// ResponseDataStatus is the payload of a Status call.
+type ResponseDataStatus struct {
+	JobID  types.JobID `json:"jobID"`
+	Status string      `json:"status"`
+}
+
+// ResponseDataRetry is the payload of a Retry call.
+type ResponseDataRetry struct {
+	JobID types.JobID `json:"jobID"`
+}
+
+// ResponseDataList is the payload of a List call.
+type ResponseDataList struct {
+	JobIDs []types.JobID `json:"jobIDs"`
+}
+
+// VersionResponse wraps a Version response together with the server that
+// produced it and any application-level error returned alongside a 2xx.
+type VersionResponse struct {
+	ServerID string
+	Data     ResponseDataVersion
+	Err      *APIError
+}
+
+// StartResponse wraps a Start response.
+type StartResponse struct {
+	ServerID string
+	Data     ResponseDataStart
+	Err      *APIError
+}
+
+// StopResponse wraps a Stop response.
+type StopResponse struct {
+	ServerID string
+	Data     ResponseDataStop
+	Err      *APIError
+}
+
+// StatusResponse wraps a Status response.
+type StatusResponse struct {
+	ServerID string
+	Data     ResponseDataStatus
+	Err      *APIError
+}
+
+// RetryResponse wraps a Retry response.
+type RetryResponse struct {
+	ServerID string
+	Data     ResponseDataRetry
+	Err      *APIError
+}
+
+// ListResponse wraps a List response.
+type ListResponse struct {
+	ServerID string
+	Data     ResponseDataList
+	Err      *APIError
+}
@@ -0,0 +1,81 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package api
+
+import "fmt"
+
+// ErrorCode is a stable, machine-readable identifier for an APIError, safe
+// to switch on across client/server versions (unlike the free-text
+// Message).
+type ErrorCode string
+
+// The error codes ConTest servers are expected to emit. Clients should treat
+// any code they don't recognize as non-retryable and non-transient.
+const (
+	// ErrJobNotFound means the requested job ID doesn't exist, or isn't
+	// visible to the requestor.
+	ErrJobNotFound = ErrorCode("job_not_found")
+	// ErrInvalidJobDescriptor means the submitted job descriptor failed
+	// validation; resubmitting it unchanged will fail the same way.
+	ErrInvalidJobDescriptor = ErrorCode("invalid_job_descriptor")
+	// ErrRequestorUnauthorized means the caller's credentials were rejected
+	// or don't grant access to the requested job/operation.
+	ErrRequestorUnauthorized = ErrorCode("requestor_unauthorized")
+	// ErrServerBusy means the server is temporarily unable to serve the
+	// request (e.g. over its concurrency limit); it's worth retrying.
+	ErrServerBusy = ErrorCode("server_busy")
+	// ErrInternal is used when the server can't classify the failure any
+	// more specifically.
+	ErrInternal = ErrorCode("internal_error")
+)
+
+// APIError is the structured error returned by every ConTest API transport
+// when the server answers a call with an error, so that callers can
+// programmatically distinguish failure modes instead of pattern-matching an
+// error string. Any other failure of a call (a bad address, dialing,
+// authenticating, writing the request, reading or decoding the reply) is
+// returned as a plain error.
+type APIError struct {
+	// StatusCode is the transport-level status the error arrived with (the
+	// HTTP status for the HTTP transport; 0 for transports, like JSON-RPC,
+	// that have no equivalent).
+	StatusCode int `json:"-"`
+	// Code is a stable identifier for the failure, one of the Err*
+	// constants above.
+	Code ErrorCode `json:"code"`
+	// Message is a human-readable description, not meant to be matched on.
+	Message string `json:"message"`
+	// Details carries optional machine-readable context, e.g. the job ID
+	// that wasn't found.
+	Details map[string]interface{} `json:"details,omitempty"`
+}
+
+// Error implements the error interface.
+func (e *APIError) Error() string {
+	if e.StatusCode != 0 {
+		return fmt.Sprintf("api error (%d) [%s]: %s", e.StatusCode, e.Code, e.Message)
+	}
+	return fmt.Sprintf("api error [%s]: %s", e.Code, e.Message)
+}
+
+// IsRetryable reports whether the same request is worth retrying as-is
+// (possibly after a backoff): true for transient server-side conditions,
+// false for anything that depends on the caller changing the request
+// (bad descriptor, unknown job, bad credentials).
+func (e *APIError) IsRetryable() bool {
+	return e.IsTransient()
+}
+
+// IsTransient reports whether the failure reflects temporary server state
+// rather than a property of the request itself.
+func (e *APIError) IsTransient() bool {
+	switch e.Code {
+	case ErrServerBusy, ErrInternal:
+		return true
+	default:
+		return false
+	}
+}
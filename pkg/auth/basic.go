@@ -0,0 +1,30 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Package auth provides api.Authenticator implementations for the API
+// client transports: HTTP Basic auth, a static bearer token, and OIDC
+// client-credentials tokens refreshed against an issuer.
+package auth
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/linuxboot/contest/pkg/xcontext"
+)
+
+// Basic authenticates with a fixed HTTP Basic Authorization header, as
+// specified in RFC 7617. Basic implements api.Authenticator.
+type Basic struct {
+	Username string
+	Password string
+}
+
+// AuthHeader returns the "Basic <base64(user:pass)>" Authorization header
+// value.
+func (b *Basic) AuthHeader(ctx xcontext.Context) (string, error) {
+	creds := fmt.Sprintf("%s:%s", b.Username, b.Password)
+	return "Basic " + base64.StdEncoding.EncodeToString([]byte(creds)), nil
+}
@@ -0,0 +1,20 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package auth
+
+import "github.com/linuxboot/contest/pkg/xcontext"
+
+// Bearer authenticates with a fixed, pre-issued bearer token, e.g. a
+// long-lived service account token or a personal access token. Bearer
+// implements api.Authenticator.
+type Bearer struct {
+	Token string
+}
+
+// AuthHeader returns the "Bearer <token>" Authorization header value.
+func (b *Bearer) AuthHeader(ctx xcontext.Context) (string, error) {
+	return "Bearer " + b.Token, nil
+}
@@ -0,0 +1,236 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// jwksCacheTTL is how long a fetched JWKS document is reused before being
+// re-fetched, so key rotation on the provider's side is picked up without
+// refetching on every single token verification.
+const jwksCacheTTL = 10 * time.Minute
+
+// JWKSVerifier verifies RS256-signed JWTs (OIDC id_tokens) against the
+// signing keys published at a provider's JWKS URI, caching them between
+// calls. It is shared by the OIDC client (which verifies the id_token it
+// just obtained) and httplistener's OIDCVerifier (which verifies the
+// id_token presented by a caller).
+//
+// Issuer and Audience must both be set: a signature check alone only proves
+// the token was minted by the configured provider, not that it was minted
+// for this application, so any other client of the same provider could
+// present a token that would otherwise pass.
+type JWKSVerifier struct {
+	JWKSURL  string
+	Issuer   string
+	Audience string
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	fetchedAt time.Time
+}
+
+type jwks struct {
+	Keys []jwk `json:"keys"`
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// Claims is a JWT's decoded payload.
+type Claims map[string]interface{}
+
+// Verify checks token's signature against the provider's current JWKS, its
+// "exp" claim against the current time, its "iss" claim against v.Issuer,
+// and that v.Audience appears in its "aud" claim, returning its decoded
+// claims on success.
+func (v *JWKSVerifier) Verify(ctx context.Context, token string) (Claims, error) {
+	if v.Issuer == "" || v.Audience == "" {
+		return nil, fmt.Errorf("JWKSVerifier is misconfigured: Issuer and Audience must both be set")
+	}
+
+	header, claims, sig, signed, err := parseJWT(token)
+	if err != nil {
+		return nil, err
+	}
+	if header.Alg != "RS256" {
+		return nil, fmt.Errorf("unsupported JWT algorithm %q, only RS256 is supported", header.Alg)
+	}
+
+	key, err := v.key(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve signing key %q: %w", header.Kid, err)
+	}
+
+	digest := sha256.Sum256(signed)
+	if err := rsa.VerifyPKCS1v15(key, crypto.SHA256, digest[:], sig); err != nil {
+		return nil, fmt.Errorf("invalid token signature: %w", err)
+	}
+
+	if exp, ok := claims["exp"].(float64); ok {
+		if time.Now().After(time.Unix(int64(exp), 0)) {
+			return nil, fmt.Errorf("token has expired")
+		}
+	}
+
+	if iss, _ := claims["iss"].(string); iss != v.Issuer {
+		return nil, fmt.Errorf("token issuer %q does not match expected issuer %q", iss, v.Issuer)
+	}
+
+	if !claims.hasAudience(v.Audience) {
+		return nil, fmt.Errorf("token audience does not include expected audience %q", v.Audience)
+	}
+
+	return claims, nil
+}
+
+// hasAudience reports whether want appears in the claims' "aud" value,
+// which per the JWT spec is either a single string or an array of strings.
+func (c Claims) hasAudience(want string) bool {
+	switch aud := c["aud"].(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if s, ok := a.(string); ok && s == want {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// key returns the RSA public key matching kid, fetching (or refreshing) the
+// JWKS document from JWKSURL if it isn't cached yet.
+func (v *JWKSVerifier) key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if key, ok := v.keys[kid]; ok && time.Since(v.fetchedAt) < jwksCacheTTL {
+		return key, nil
+	}
+
+	keys, err := fetchJWKS(ctx, v.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	v.keys = keys
+	v.fetchedAt = time.Now()
+
+	key, ok := v.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("no key with kid %q in JWKS at %s", kid, v.JWKSURL)
+	}
+	return key, nil
+}
+
+func fetchJWKS(ctx context.Context, url string) (map[string]*rsa.PublicKey, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("JWKS endpoint returned %s: %s", resp.Status, body)
+	}
+
+	var doc jwks
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return nil, fmt.Errorf("invalid JWKS document: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey, len(doc.Keys))
+	for _, k := range doc.Keys {
+		if k.Kty != "RSA" || k.Kid == "" {
+			continue
+		}
+		pub, err := rsaPublicKeyFromJWK(k)
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %q in JWKS: %w", k.Kid, err)
+		}
+		keys[k.Kid] = pub
+	}
+	return keys, nil
+}
+
+func rsaPublicKeyFromJWK(k jwk) (*rsa.PublicKey, error) {
+	nBytes, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid modulus: %w", err)
+	}
+	eBytes, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid exponent: %w", err)
+	}
+	return &rsa.PublicKey{
+		N: new(big.Int).SetBytes(nBytes),
+		E: int(new(big.Int).SetBytes(eBytes).Int64()),
+	}, nil
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// parseJWT splits a compact JWT into its header and decoded claims plus the
+// raw signature, along with the exact "header.payload" bytes the signature
+// covers.
+func parseJWT(token string) (header jwtHeader, claims Claims, sig []byte, signed []byte, err error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return jwtHeader{}, nil, nil, nil, fmt.Errorf("malformed JWT: expected 3 dot-separated parts, got %d", len(parts))
+	}
+
+	headerRaw, err := base64.RawURLEncoding.DecodeString(parts[0])
+	if err != nil {
+		return jwtHeader{}, nil, nil, nil, fmt.Errorf("invalid JWT header encoding: %w", err)
+	}
+	if err := json.Unmarshal(headerRaw, &header); err != nil {
+		return jwtHeader{}, nil, nil, nil, fmt.Errorf("invalid JWT header: %w", err)
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return jwtHeader{}, nil, nil, nil, fmt.Errorf("invalid JWT payload encoding: %w", err)
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return jwtHeader{}, nil, nil, nil, fmt.Errorf("invalid JWT claims: %w", err)
+	}
+
+	sig, err = base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return jwtHeader{}, nil, nil, nil, fmt.Errorf("invalid JWT signature encoding: %w", err)
+	}
+
+	signed = []byte(parts[0] + "." + parts[1])
+	return header, claims, sig, signed, nil
+}
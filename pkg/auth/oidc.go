@@ -0,0 +1,160 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package auth
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/linuxboot/contest/pkg/xcontext"
+)
+
+// tokenExpiryMargin is how much validity a cached token must still have
+// left for AuthHeader to reuse it instead of fetching a fresh one.
+const tokenExpiryMargin = 30 * time.Second
+
+// OIDC authenticates against an OIDC provider using the client-credentials
+// grant: it discovers the token and JWKS endpoints from the issuer's
+// well-known configuration document, exchanges ClientID/ClientSecret for an
+// id_token, verifies that id_token against the issuer's JWKS before trusting
+// it, and transparently refreshes it once it's close to expiring. OIDC
+// implements api.Authenticator.
+type OIDC struct {
+	// Issuer is the OIDC issuer URL, e.g. "https://auth.example.com". Its
+	// "/.well-known/openid-configuration" document is fetched once, on
+	// first use, to locate the token and JWKS endpoints.
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	// Scopes is requested in addition to "openid", which is always
+	// requested so the token endpoint issues an id_token.
+	Scopes []string
+
+	mu        sync.Mutex
+	tokenURL  string
+	verifier  *JWKSVerifier
+	token     string
+	expiresAt time.Time
+}
+
+type oidcDiscoveryDocument struct {
+	TokenEndpoint string `json:"token_endpoint"`
+	JWKSURI       string `json:"jwks_uri"`
+}
+
+type oidcTokenResponse struct {
+	IDToken   string `json:"id_token"`
+	ExpiresIn int    `json:"expires_in"`
+}
+
+// AuthHeader returns the "Bearer <id_token>" Authorization header value,
+// reusing the cached token until it's within tokenExpiryMargin of expiring.
+func (o *OIDC) AuthHeader(ctx xcontext.Context) (string, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	if o.token != "" && time.Now().Add(tokenExpiryMargin).Before(o.expiresAt) {
+		return "Bearer " + o.token, nil
+	}
+
+	if o.tokenURL == "" {
+		tokenURL, jwksURI, err := o.discover(ctx)
+		if err != nil {
+			return "", fmt.Errorf("OIDC discovery against %q failed: %w", o.Issuer, err)
+		}
+		o.tokenURL = tokenURL
+		o.verifier = &JWKSVerifier{JWKSURL: jwksURI, Issuer: o.Issuer, Audience: o.ClientID}
+	}
+
+	token, expiresIn, err := o.fetchToken(ctx)
+	if err != nil {
+		return "", fmt.Errorf("failed to obtain OIDC token: %w", err)
+	}
+	if _, err := o.verifier.Verify(ctx, token); err != nil {
+		return "", fmt.Errorf("id_token failed JWKS verification: %w", err)
+	}
+	o.token = token
+	o.expiresAt = time.Now().Add(time.Duration(expiresIn) * time.Second)
+
+	return "Bearer " + o.token, nil
+}
+
+func (o *OIDC) discover(ctx xcontext.Context) (tokenURL, jwksURI string, err error) {
+	discoveryURL := strings.TrimRight(o.Issuer, "/") + "/.well-known/openid-configuration"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, discoveryURL, nil)
+	if err != nil {
+		return "", "", err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", "", fmt.Errorf("discovery endpoint returned %s: %s", resp.Status, body)
+	}
+
+	var doc oidcDiscoveryDocument
+	if err := json.Unmarshal(body, &doc); err != nil {
+		return "", "", fmt.Errorf("invalid discovery document: %w", err)
+	}
+	if doc.TokenEndpoint == "" {
+		return "", "", fmt.Errorf("discovery document has no token_endpoint")
+	}
+	if doc.JWKSURI == "" {
+		return "", "", fmt.Errorf("discovery document has no jwks_uri")
+	}
+	return doc.TokenEndpoint, doc.JWKSURI, nil
+}
+
+func (o *OIDC) fetchToken(ctx xcontext.Context) (string, int, error) {
+	scopes := append([]string{"openid"}, o.Scopes...)
+
+	form := url.Values{}
+	form.Set("grant_type", "client_credentials")
+	form.Set("client_id", o.ClientID)
+	form.Set("client_secret", o.ClientSecret)
+	form.Set("scope", strings.Join(scopes, " "))
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, o.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", 0, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return "", 0, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", 0, fmt.Errorf("token endpoint returned %s: %s", resp.Status, body)
+	}
+
+	var tok oidcTokenResponse
+	if err := json.Unmarshal(body, &tok); err != nil {
+		return "", 0, fmt.Errorf("invalid token response: %w", err)
+	}
+	if tok.IDToken == "" {
+		return "", 0, fmt.Errorf("token response has no id_token")
+	}
+	return tok.IDToken, tok.ExpiresIn, nil
+}
@@ -19,9 +19,6 @@ import (
 	"github.com/linuxboot/contest/pkg/job"
 	"github.com/linuxboot/contest/pkg/types"
 	"github.com/linuxboot/contest/pkg/xcontext"
-	"github.com/linuxboot/contest/plugins/listeners/httplistener"
-
-	"github.com/insomniacslk/xjson"
 )
 
 // HttpPartiallyDecodedResponse is a httplistener.HTTPAPIResponse, but with the Data not fully decoded yet
@@ -29,13 +26,17 @@ type HTTPPartiallyDecodedResponse struct {
 	ServerID string
 	Type     string
 	Data     json.RawMessage
-	Error    *xjson.Error
+	Error    *api.APIError
 }
 
 // HTTP communicates with ConTest Server via http(s)/json transport
 // HTTP implements the Transport interface
 type HTTP struct {
 	Addr string
+	// Auth, if set, authenticates every request by attaching the
+	// Authorization header it returns. Leaving it nil keeps existing
+	// unauthenticated deployments working unchanged.
+	Auth api.Authenticator
 }
 
 func (h *HTTP) Version(ctx xcontext.Context, requestor string) (*api.VersionResponse, error) {
@@ -160,7 +161,21 @@ func (h *HTTP) request(ctx xcontext.Context, requestor string, verb string, para
 		logger = logger.WithField(k, v)
 	}
 	logger.Infof("Requesting URL %s with requestor ID '%s'\n", u.String(), requestor)
-	resp, err := http.PostForm(u.String(), params)
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(params.Encode()))
+	if err != nil {
+		return nil, fmt.Errorf("cannot build HTTP request: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if h.Auth != nil {
+		authHeader, err := h.Auth.AuthHeader(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate request: %w", err)
+		}
+		req.Header.Set("Authorization", authHeader)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
 	if err != nil {
 		return nil, fmt.Errorf("HTTP POST failed: %v", err)
 	}
@@ -171,21 +186,21 @@ func (h *HTTP) request(ctx xcontext.Context, requestor string, verb string, para
 	}
 	xcontext.LoggerFrom(ctx).Infof("The server responded with status %s\n", resp.Status)
 
-	var apiResp HTTPPartiallyDecodedResponse
-	if resp.StatusCode == http.StatusOK {
-		// the Data field of apiResp will result in a map[string]interface{}
-		if err := json.Unmarshal(body, &apiResp); err != nil {
-			return nil, fmt.Errorf("response is not a valid HTTP API response object: '%s': %v", body, err)
-		}
-		if err != nil {
-			return nil, fmt.Errorf("cannot marshal HTTPAPIResponse: %v", err)
-		}
-	} else {
-		var apiErr httplistener.HTTPAPIError
+	if resp.StatusCode != http.StatusOK {
+		var apiErr api.APIError
 		if err := json.Unmarshal(body, &apiErr); err != nil {
-			return nil, fmt.Errorf("response is not a valid HTTP API Error object: '%s': %v", body, err)
+			// not a structured error body (e.g. a proxy's own error page):
+			// still report it as an APIError, carrying the raw body.
+			apiErr = api.APIError{Code: api.ErrInternal, Message: string(body)}
 		}
-		apiResp.Error = xjson.NewError(errors.New(apiErr.Msg))
+		apiErr.StatusCode = resp.StatusCode
+		return nil, &apiErr
+	}
+
+	// the Data field of apiResp will result in a map[string]interface{}
+	var apiResp HTTPPartiallyDecodedResponse
+	if err := json.Unmarshal(body, &apiResp); err != nil {
+		return nil, fmt.Errorf("response is not a valid HTTP API response object: '%s': %v", body, err)
 	}
 
 	return &apiResp, nil
@@ -0,0 +1,457 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Package jsonrpc implements the api.Transport interface on top of
+// JSON-RPC 2.0, carried over a plain TCP connection or a websocket. It is
+// meant as a drop-in alternative to pkg/transport/http for operators who
+// want to put agents behind a firewall or proxy the API through a broker
+// that only forwards a persistent socket, rather than an HTTP endpoint.
+package jsonrpc
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/linuxboot/contest/pkg/api"
+	"github.com/linuxboot/contest/pkg/job"
+	"github.com/linuxboot/contest/pkg/types"
+	"github.com/linuxboot/contest/pkg/xcontext"
+)
+
+const (
+	// initialBackoff is the delay before the first reconnection attempt.
+	initialBackoff = 100 * time.Millisecond
+	// maxBackoff caps the delay between reconnection attempts.
+	maxBackoff = 10 * time.Second
+)
+
+// idempotentMethods are the verbs safe to silently retry against a new
+// connection after a transient failure: a reader can't tell whether the
+// previous attempt reached the server, so retrying a non-idempotent verb
+// like "start" risks submitting the same job twice.
+var idempotentMethods = map[string]bool{
+	"version": true,
+	"status":  true,
+	"list":    true,
+}
+
+// JSONRPC communicates with ConTest Server via JSON-RPC 2.0, over TCP or
+// websockets depending on the scheme of Addr ("tcp://" or "ws(s)://").
+// JSONRPC implements the Transport interface.
+type JSONRPC struct {
+	// Addr is the server address, e.g. "tcp://contest-server:4242" or
+	// "ws://contest-server:4242/rpc".
+	Addr string
+
+	mu     sync.Mutex
+	sess   *session
+	nextID uint64
+}
+
+// rpcConn abstracts over the two wire carriers this package supports, so
+// the request/response machinery doesn't need to know whether it is
+// talking to a raw TCP socket or a websocket.
+type rpcConn interface {
+	WriteMessage(b []byte) error
+	ReadMessage() ([]byte, error)
+	Close() error
+}
+
+type rpcRequest struct {
+	JSONRPC string      `json:"jsonrpc"`
+	Method  string      `json:"method"`
+	Params  interface{} `json:"params"`
+	ID      uint64      `json:"id"`
+}
+
+// rpcResponse carries the same api.APIError the HTTP transport uses, so
+// callers can type-switch on the returned error the same way regardless of
+// which transport they picked.
+type rpcResponse struct {
+	JSONRPC string          `json:"jsonrpc"`
+	Result  json.RawMessage `json:"result"`
+	Error   *api.APIError   `json:"error"`
+	ID      uint64          `json:"id"`
+}
+
+// rpcResult is what a pending call is woken up with: either the matching
+// response, or the error that tore the connection down before one arrived.
+type rpcResult struct {
+	resp *rpcResponse
+	err  error
+}
+
+// session owns one rpcConn and the single goroutine that reads from it,
+// dispatching each response to the pending call waiting on its ID. Calls
+// never read from the conn themselves, so two calls sharing a connection
+// can't race over who gets the next frame, and a call that gives up on ctx
+// cancellation doesn't leave a reader goroutine blocked in ReadMessage:
+// that goroutine belongs to the session, not the call, and keeps running
+// until the conn itself fails.
+type session struct {
+	conn rpcConn
+
+	mu      sync.Mutex
+	pending map[uint64]chan rpcResult
+	err     error // set once the read loop has exited
+}
+
+func newSession(conn rpcConn) *session {
+	s := &session{conn: conn, pending: make(map[uint64]chan rpcResult)}
+	go s.readLoop()
+	return s
+}
+
+func (s *session) readLoop() {
+	for {
+		b, err := s.conn.ReadMessage()
+		if err != nil {
+			s.fail(err)
+			return
+		}
+		var resp rpcResponse
+		if err := json.Unmarshal(b, &resp); err != nil {
+			// We can no longer trust framing/ID alignment on this
+			// connection, so treat it like any other fatal read error.
+			s.fail(fmt.Errorf("invalid JSON-RPC response: %w", err))
+			return
+		}
+		s.deliver(&resp)
+	}
+}
+
+// register records a channel that will receive the response for id, unless
+// the session has already failed, in which case the channel is resolved
+// with that failure immediately.
+func (s *session) register(id uint64) chan rpcResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ch := make(chan rpcResult, 1)
+	if s.err != nil {
+		ch <- rpcResult{nil, s.err}
+		return ch
+	}
+	s.pending[id] = ch
+	return ch
+}
+
+// unregister abandons a pending call, e.g. because its context was
+// cancelled before a response arrived. A response that shows up for it
+// afterwards is simply dropped by deliver.
+func (s *session) unregister(id uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, id)
+}
+
+func (s *session) deliver(resp *rpcResponse) {
+	s.mu.Lock()
+	ch, ok := s.pending[resp.ID]
+	if ok {
+		delete(s.pending, resp.ID)
+	}
+	s.mu.Unlock()
+	if ok {
+		ch <- rpcResult{resp, nil}
+	}
+}
+
+// fail tears the session down: every call still waiting on a response is
+// woken up with err, and any call that registers from now on is failed the
+// same way.
+func (s *session) fail(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.err != nil {
+		return
+	}
+	s.err = err
+	for id, ch := range s.pending {
+		ch <- rpcResult{nil, err}
+		delete(s.pending, id)
+	}
+}
+
+func (j *JSONRPC) Version(ctx xcontext.Context, requestor string) (*api.VersionResponse, error) {
+	var data api.ResponseDataVersion
+	if err := j.call(ctx, "version", requestParams{Requestor: requestor}, &data); err != nil {
+		return nil, err
+	}
+	return &api.VersionResponse{Data: data}, nil
+}
+
+func (j *JSONRPC) Start(ctx xcontext.Context, requestor string, jobDescriptor string) (*api.StartResponse, error) {
+	var data api.ResponseDataStart
+	params := requestParams{Requestor: requestor, JobDesc: jobDescriptor}
+	if err := j.call(ctx, "start", params, &data); err != nil {
+		return nil, err
+	}
+	return &api.StartResponse{Data: data}, nil
+}
+
+func (j *JSONRPC) Stop(ctx xcontext.Context, requestor string, jobID types.JobID) (*api.StopResponse, error) {
+	var data api.ResponseDataStop
+	params := requestParams{Requestor: requestor, JobID: int(jobID)}
+	if err := j.call(ctx, "stop", params, &data); err != nil {
+		return nil, err
+	}
+	return &api.StopResponse{Data: data}, nil
+}
+
+func (j *JSONRPC) Status(ctx xcontext.Context, requestor string, jobID types.JobID) (*api.StatusResponse, error) {
+	var data api.ResponseDataStatus
+	params := requestParams{Requestor: requestor, JobID: int(jobID)}
+	if err := j.call(ctx, "status", params, &data); err != nil {
+		return nil, err
+	}
+	return &api.StatusResponse{Data: data}, nil
+}
+
+func (j *JSONRPC) Retry(ctx xcontext.Context, requestor string, jobID types.JobID) (*api.RetryResponse, error) {
+	var data api.ResponseDataRetry
+	params := requestParams{Requestor: requestor, JobID: int(jobID)}
+	if err := j.call(ctx, "retry", params, &data); err != nil {
+		return nil, err
+	}
+	return &api.RetryResponse{Data: data}, nil
+}
+
+func (j *JSONRPC) List(ctx xcontext.Context, requestor string, states []job.State, tags []string) (*api.ListResponse, error) {
+	var data api.ResponseDataList
+	sts := make([]string, len(states))
+	for i, st := range states {
+		sts[i] = st.String()
+	}
+	params := requestParams{Requestor: requestor, States: sts, Tags: tags}
+	if err := j.call(ctx, "list", params, &data); err != nil {
+		return nil, err
+	}
+	return &api.ListResponse{Data: data}, nil
+}
+
+// requestParams is the params object sent with every JSON-RPC call. Verbs
+// that don't need a given field simply leave it at its zero value, which is
+// omitted from the wire encoding.
+type requestParams struct {
+	Requestor string   `json:"requestor"`
+	JobDesc   string   `json:"jobDesc,omitempty"`
+	JobID     int      `json:"jobID,omitempty"`
+	States    []string `json:"states,omitempty"`
+	Tags      []string `json:"tags,omitempty"`
+}
+
+// call sends a JSON-RPC request for the given method and decodes the
+// result into v. On a transient connection failure it reconnects and
+// retries with backoff, but only for idempotentMethods: for any other verb,
+// a failure after the request may already have reached the server, so it is
+// returned to the caller instead of being silently retried. A failure to
+// dial the server, on the first attempt or on a reconnect, is returned at
+// once and is not retried. It aborts as soon as ctx is cancelled or its
+// deadline fires.
+func (j *JSONRPC) call(ctx xcontext.Context, method string, params requestParams, v interface{}) error {
+	logger := xcontext.LoggerFrom(ctx)
+
+	req := rpcRequest{JSONRPC: "2.0", Method: method, Params: params}
+
+	backoff := initialBackoff
+	for attempt := 0; ; attempt++ {
+		sess, err := j.getSession(ctx)
+		if err != nil {
+			return fmt.Errorf("cannot connect to %s: %w", j.Addr, err)
+		}
+
+		req.ID = j.newID()
+		logger.WithField("method", method).WithField("id", req.ID).Infof("sending JSON-RPC request to %s", j.Addr)
+
+		resp, err := j.roundTrip(ctx, sess, req)
+		if err == nil {
+			if resp.Error != nil {
+				return resp.Error
+			}
+			if len(resp.Result) == 0 {
+				return nil
+			}
+			return json.Unmarshal(resp.Result, v)
+		}
+
+		// The context was cancelled or timed out: don't retry, the caller
+		// is no longer interested in the result.
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+
+		j.closeSession(sess)
+
+		if !idempotentMethods[method] {
+			return fmt.Errorf("JSON-RPC call %q failed: %w", method, err)
+		}
+
+		// Anything else is treated as a transient transport failure: drop
+		// the connection and retry with an exponential backoff, bounded by
+		// the context deadline.
+		logger.Warnf("JSON-RPC call %q failed (attempt %d): %v, reconnecting in %s", method, attempt+1, err, backoff)
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// roundTrip writes req on sess's connection and waits for its matching
+// response, returning early if ctx is done before one arrives. It never
+// reads from the connection itself; sess's own reader goroutine does that
+// and dispatches by ID, so roundTrip giving up early never leaves a reader
+// blocked or racing with the next call on the same connection.
+func (j *JSONRPC) roundTrip(ctx xcontext.Context, sess *session, req rpcRequest) (*rpcResponse, error) {
+	payload, err := json.Marshal(req)
+	if err != nil {
+		return nil, fmt.Errorf("cannot marshal JSON-RPC request: %w", err)
+	}
+
+	ch := sess.register(req.ID)
+	if err := sess.conn.WriteMessage(payload); err != nil {
+		sess.unregister(req.ID)
+		return nil, fmt.Errorf("cannot write JSON-RPC request: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+		sess.unregister(req.ID)
+		return nil, ctx.Err()
+	case res := <-ch:
+		return res.resp, res.err
+	}
+}
+
+func (j *JSONRPC) newID() uint64 {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.nextID++
+	return j.nextID
+}
+
+// getSession returns the current session, dialing a new connection and
+// starting its reader goroutine if necessary.
+func (j *JSONRPC) getSession(ctx xcontext.Context) (*session, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.sess != nil {
+		return j.sess, nil
+	}
+	conn, err := dial(ctx, j.Addr)
+	if err != nil {
+		return nil, err
+	}
+	j.sess = newSession(conn)
+	return j.sess, nil
+}
+
+// closeSession drops sess's connection, provided it is still the current
+// one: a concurrent call may already have replaced it with a fresh session,
+// in which case there is nothing for this caller to tear down.
+func (j *JSONRPC) closeSession(sess *session) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.sess == sess {
+		j.sess.conn.Close()
+		j.sess = nil
+	}
+}
+
+// Close releases the underlying connection, if any. It is safe to call
+// Close on a JSONRPC that has never connected, or more than once.
+func (j *JSONRPC) Close() error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if j.sess != nil {
+		j.sess.conn.Close()
+		j.sess = nil
+	}
+	return nil
+}
+
+func dial(ctx xcontext.Context, addr string) (rpcConn, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse server address '%s': %v", addr, err)
+	}
+	switch u.Scheme {
+	case "ws", "wss":
+		dialer := websocket.Dialer{HandshakeTimeout: 10 * time.Second}
+		c, _, err := dialer.DialContext(goContext(ctx), addr, nil)
+		if err != nil {
+			return nil, err
+		}
+		return &wsConn{c}, nil
+	case "tcp":
+		var d net.Dialer
+		c, err := d.DialContext(goContext(ctx), "tcp", u.Host)
+		if err != nil {
+			return nil, err
+		}
+		return &tcpConn{conn: c, r: bufio.NewReader(c)}, nil
+	case "":
+		return nil, fmt.Errorf("server URL scheme not specified, use tcp://, ws:// or wss://")
+	default:
+		return nil, fmt.Errorf("unsupported URL scheme '%s', please specify tcp, ws or wss", u.Scheme)
+	}
+}
+
+// goContext adapts an xcontext.Context to a stdlib context.Context, which is
+// all net.Dialer and websocket.Dialer understand.
+func goContext(ctx xcontext.Context) context.Context {
+	return ctx
+}
+
+// tcpConn carries newline-delimited JSON-RPC messages over a raw TCP
+// connection.
+type tcpConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+func (c *tcpConn) WriteMessage(b []byte) error {
+	_, err := c.conn.Write(append(b, '\n'))
+	return err
+}
+
+func (c *tcpConn) ReadMessage() ([]byte, error) {
+	return c.r.ReadBytes('\n')
+}
+
+func (c *tcpConn) Close() error {
+	return c.conn.Close()
+}
+
+// wsConn carries one JSON-RPC message per websocket text frame.
+type wsConn struct {
+	conn *websocket.Conn
+}
+
+func (c *wsConn) WriteMessage(b []byte) error {
+	return c.conn.WriteMessage(websocket.TextMessage, b)
+}
+
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	_, b, err := c.conn.ReadMessage()
+	return b, err
+}
+
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
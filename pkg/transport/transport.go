@@ -0,0 +1,45 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Package transport selects and constructs the api.Transport implementation
+// used by ConTest clients (the CLI and any other API consumer), so that
+// callers can pick a transport by name (e.g. via a `--transport` flag)
+// without depending on a specific implementation package.
+package transport
+
+import (
+	"fmt"
+
+	"github.com/linuxboot/contest/pkg/api"
+	"github.com/linuxboot/contest/pkg/transport/http"
+	"github.com/linuxboot/contest/pkg/transport/jsonrpc"
+)
+
+// Supported transport kinds, as accepted by New and by the CLI's
+// `--transport` flag.
+const (
+	HTTP    = "http"
+	JSONRPC = "jsonrpc"
+)
+
+// New constructs the api.Transport implementation named by kind, pointed at
+// addr. It defaults to HTTP when kind is empty, so existing callers that
+// don't select a transport keep working unchanged.
+//
+// New itself is the dispatch point a `--transport` CLI flag would call into,
+// but this tree has no cmd/ or package main for such a flag (or for any
+// other CLI entry point) to live in, so New has no caller yet and the
+// jsonrpc path it can select is only reachable by constructing a
+// transport.JSONRPC directly.
+func New(kind string, addr string) (api.Transport, error) {
+	switch kind {
+	case "", HTTP:
+		return &http.HTTP{Addr: addr}, nil
+	case JSONRPC:
+		return &jsonrpc.JSONRPC{Addr: addr}, nil
+	default:
+		return nil, fmt.Errorf("unknown transport %q, expected %q or %q", kind, HTTP, JSONRPC)
+	}
+}
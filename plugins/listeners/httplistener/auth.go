@@ -0,0 +1,132 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package httplistener
+
+import (
+	"crypto/subtle"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/linuxboot/contest/pkg/api"
+	"github.com/linuxboot/contest/pkg/auth"
+)
+
+// Verifier authenticates an incoming request and returns the requestor
+// identity to trust for it, mirroring api.Authenticator on the client side.
+// Handlers never see the client-supplied "requestor" form value directly:
+// Middleware overwrites it with whatever Verify returns, so a caller can't
+// impersonate another requestor just by setting the form field.
+type Verifier interface {
+	Verify(r *http.Request) (requestor string, err error)
+}
+
+// Middleware wraps next so every request is authenticated by v before
+// reaching it. On success, the request's "requestor" form value is
+// overwritten with the verified identity; on failure, it writes a 401
+// api.APIError and never calls next.
+func Middleware(v Verifier, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requestor, err := v.Verify(r)
+		if err != nil {
+			WriteError(w, &api.APIError{
+				Code:    api.ErrRequestorUnauthorized,
+				Message: fmt.Sprintf("authentication failed: %v", err),
+			})
+			return
+		}
+
+		if err := r.ParseForm(); err != nil {
+			WriteError(w, &api.APIError{
+				Code:    api.ErrInvalidJobDescriptor,
+				Message: fmt.Sprintf("failed to parse request: %v", err),
+			})
+			return
+		}
+		r.Form.Set("requestor", requestor)
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// BasicVerifier accepts HTTP Basic auth with a fixed username/password and
+// trusts the username as the requestor.
+type BasicVerifier struct {
+	Username string
+	Password string
+}
+
+func (b *BasicVerifier) Verify(r *http.Request) (string, error) {
+	username, password, ok := r.BasicAuth()
+	if !ok {
+		return "", fmt.Errorf("missing Basic authorization header")
+	}
+	if subtle.ConstantTimeCompare([]byte(username), []byte(b.Username)) != 1 ||
+		subtle.ConstantTimeCompare([]byte(password), []byte(b.Password)) != 1 {
+		return "", fmt.Errorf("invalid credentials")
+	}
+	return username, nil
+}
+
+// BearerVerifier accepts a fixed, pre-issued bearer token and trusts
+// Requestor as the requestor for any request bearing it.
+type BearerVerifier struct {
+	Token     string
+	Requestor string
+}
+
+func (b *BearerVerifier) Verify(r *http.Request) (string, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return "", err
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(b.Token)) != 1 {
+		return "", fmt.Errorf("invalid bearer token")
+	}
+	return b.Requestor, nil
+}
+
+// OIDCVerifier verifies a bearer id_token against an OIDC provider's JWKS
+// and trusts RequestorClaim (e.g. "email" or "sub") of its verified claims
+// as the requestor. Verifier must have its Issuer and Audience set to the
+// values expected for this server: JWKSVerifier.Verify rejects a token that
+// doesn't match them, so a token minted by the same provider for some other
+// client isn't enough on its own to authenticate here.
+type OIDCVerifier struct {
+	Verifier       *auth.JWKSVerifier
+	RequestorClaim string
+}
+
+func (o *OIDCVerifier) Verify(r *http.Request) (string, error) {
+	token, err := bearerToken(r)
+	if err != nil {
+		return "", err
+	}
+
+	claims, err := o.Verifier.Verify(r.Context(), token)
+	if err != nil {
+		return "", fmt.Errorf("id_token verification failed: %w", err)
+	}
+
+	claim := o.RequestorClaim
+	if claim == "" {
+		claim = "sub"
+	}
+	requestor, ok := claims[claim].(string)
+	if !ok || requestor == "" {
+		return "", fmt.Errorf("id_token has no %q claim", claim)
+	}
+	return requestor, nil
+}
+
+func bearerToken(r *http.Request) (string, error) {
+	header := r.Header.Get("Authorization")
+	const prefix = "Bearer "
+	if !strings.HasPrefix(header, prefix) {
+		return "", fmt.Errorf("missing Bearer authorization header")
+	}
+	return strings.TrimPrefix(header, prefix), nil
+}
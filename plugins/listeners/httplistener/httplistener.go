@@ -0,0 +1,62 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Package httplistener implements the server side of the HTTP/form-encoded
+// ConTest API consumed by pkg/transport/http.
+package httplistener
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/linuxboot/contest/pkg/api"
+)
+
+// HTTPAPIResponse is the envelope every successful call's JSON body is
+// wrapped in. Field names match pkg/transport/http.HTTPPartiallyDecodedResponse
+// so the client can decode it directly.
+type HTTPAPIResponse struct {
+	ServerID string      `json:"ServerID"`
+	Type     string      `json:"Type"`
+	Data     interface{} `json:"Data"`
+}
+
+// WriteResponse writes a successful 200 OK response for verb, carrying data
+// as its Data payload.
+func WriteResponse(w http.ResponseWriter, serverID, verb string, data interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_ = json.NewEncoder(w).Encode(HTTPAPIResponse{ServerID: serverID, Type: verb, Data: data})
+}
+
+// WriteError writes apiErr as a structured JSON body, using apiErr.StatusCode
+// if set, or the status that best matches its Code otherwise.
+func WriteError(w http.ResponseWriter, apiErr *api.APIError) {
+	status := apiErr.StatusCode
+	if status == 0 {
+		status = statusForCode(apiErr.Code)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(apiErr)
+}
+
+// statusForCode maps a stable api.ErrorCode to the HTTP status a client
+// should see it as, so the two ends agree without the caller having to set
+// StatusCode by hand for every error site.
+func statusForCode(code api.ErrorCode) int {
+	switch code {
+	case api.ErrJobNotFound:
+		return http.StatusNotFound
+	case api.ErrInvalidJobDescriptor:
+		return http.StatusBadRequest
+	case api.ErrRequestorUnauthorized:
+		return http.StatusUnauthorized
+	case api.ErrServerBusy:
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,152 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Package capture streams a single reader (a process's stdout/stderr pipe,
+// an HTTP response body, ...) line-by-line, emitting one chunked event per
+// flush interval with the lines that arrived during it, instead of
+// buffering the whole output until the reader hits EOF. Emitting is
+// synchronous with the scan, so a slow event sink applies backpressure on
+// the reader rather than the caller buffering unboundedly or dropping
+// data. It is shared by every teststep plugin that streams output this way
+// (bios_settings_set, hwaas, ...), so the buffering/flushing policy and its
+// tunables live in one place.
+package capture
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/linuxboot/contest/pkg/xcontext"
+)
+
+// Defaults applied by New whenever the corresponding Options field is left
+// at its zero value.
+const (
+	DefaultMaxLineSize      = 64 * 1024 // 64KiB
+	DefaultFlushInterval    = 200 * time.Millisecond
+	DefaultMaxCapturedBytes = 1 << 20 // 1MiB
+)
+
+// EmitFunc delivers one flushed chunk of output, e.g. by wrapping the
+// caller's own event name/payload/testevent.Emitter.
+type EmitFunc func(ctx xcontext.Context, chunk string) error
+
+// Options tunes a Capture's buffering. A zero value field falls back to the
+// package's own default.
+type Options struct {
+	MaxLineSize      int
+	FlushInterval    time.Duration
+	MaxCapturedBytes int
+}
+
+// Capture streams a single reader, handing flushed chunks to Emit while
+// keeping a bounded aggregate of everything captured so far, so a caller can
+// still inspect the output once the reader is done.
+type Capture struct {
+	emit EmitFunc
+
+	maxLine  int
+	flush    time.Duration
+	maxBytes int
+
+	aggregate bytes.Buffer
+}
+
+// New builds a Capture that calls emit with each flushed chunk.
+func New(emit EmitFunc, opts Options) *Capture {
+	maxLine := opts.MaxLineSize
+	if maxLine <= 0 {
+		maxLine = DefaultMaxLineSize
+	}
+	flush := opts.FlushInterval
+	if flush <= 0 {
+		flush = DefaultFlushInterval
+	}
+	maxBytes := opts.MaxCapturedBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxCapturedBytes
+	}
+	return &Capture{emit: emit, maxLine: maxLine, flush: flush, maxBytes: maxBytes}
+}
+
+// Run scans r line-by-line until EOF or ctx is done, emitting one chunk per
+// flush interval's worth of lines.
+func (c *Capture) Run(ctx xcontext.Context, r io.Reader) error {
+	lines := make(chan string)
+	scanDone := make(chan error, 1)
+
+	go func() {
+		defer close(lines)
+		scanner := bufio.NewScanner(r)
+		scanner.Buffer(make([]byte, 4096), c.maxLine)
+		for scanner.Scan() {
+			select {
+			case lines <- scanner.Text():
+			case <-ctx.Done():
+				scanDone <- ctx.Err()
+				return
+			}
+		}
+		scanDone <- scanner.Err()
+	}()
+
+	ticker := time.NewTicker(c.flush)
+	defer ticker.Stop()
+
+	var pending strings.Builder
+	flushPending := func() error {
+		if pending.Len() == 0 {
+			return nil
+		}
+		chunk := pending.String()
+		pending.Reset()
+		c.append(chunk)
+		return c.emit(ctx, chunk)
+	}
+
+	for {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				if err := flushPending(); err != nil {
+					return err
+				}
+				return <-scanDone
+			}
+			if pending.Len() > 0 {
+				pending.WriteByte('\n')
+			}
+			pending.WriteString(line)
+		case <-ticker.C:
+			if err := flushPending(); err != nil {
+				return err
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// append adds chunk to the aggregate, keeping only the most recent maxBytes
+// of it: once full, the oldest bytes are dropped to make room for the
+// newest rather than growing the buffer without bound.
+func (c *Capture) append(chunk string) {
+	c.aggregate.WriteString(chunk)
+	c.aggregate.WriteByte('\n')
+	if over := c.aggregate.Len() - c.maxBytes; over > 0 {
+		b := append([]byte(nil), c.aggregate.Bytes()[over:]...)
+		c.aggregate.Reset()
+		c.aggregate.Write(b)
+	}
+}
+
+// Bytes returns the most recent output captured so far, at most
+// MaxCapturedBytes of it; anything older has already been dropped.
+func (c *Capture) Bytes() []byte {
+	return c.aggregate.Bytes()
+}
@@ -0,0 +1,124 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package transport
+
+import (
+	"io"
+	"sync"
+	"time"
+)
+
+// deadlineTimer models net.Conn-style deadlines for an operation that has no
+// native deadline support of its own (an ssh.Session's pipes): set arms (or,
+// for a zero time, disarms) a timer that closes a channel when it fires, via
+// time.AfterFunc, so anything selecting on Done() unblocks at the deadline
+// instead of hanging forever. Each call to set rearms a fresh channel, so a
+// deadline that already fired doesn't leak into the next one.
+type deadlineTimer struct {
+	mu    sync.Mutex
+	timer *time.Timer
+	done  chan struct{}
+}
+
+func newDeadlineTimer() *deadlineTimer {
+	return &deadlineTimer{done: make(chan struct{})}
+}
+
+func (d *deadlineTimer) set(t time.Time) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.timer != nil {
+		d.timer.Stop()
+	}
+	if t.IsZero() {
+		d.timer = nil
+		d.done = make(chan struct{})
+		return
+	}
+	done := make(chan struct{})
+	d.done = done
+	d.timer = time.AfterFunc(time.Until(t), func() { close(done) })
+}
+
+func (d *deadlineTimer) Done() <-chan struct{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.done
+}
+
+// deadlineReader wraps an io.Reader so that Read returns ErrTimeout once dl's
+// deadline fires, instead of blocking on the underlying read indefinitely.
+//
+// The underlying read runs on a single background goroutine, started lazily
+// on the first Read and living for as long as r.r keeps producing data. This
+// goroutine owns a private buffer and never touches the caller's p directly:
+// if a deadline fires while a read is still in flight, Read returns early but
+// the goroutine is still out there, and if it were writing into the caller's
+// p, a caller that reuses p afterwards (e.g. bufio.Scanner, which does) would
+// race with it. Routing every read through readResult.b keeps the two
+// memory spaces disjoint regardless of how Read and the background read
+// interleave.
+type deadlineReader struct {
+	r  io.Reader
+	dl *deadlineTimer
+
+	once sync.Once
+	ch   chan readResult
+	buf  []byte
+}
+
+type readResult struct {
+	b   []byte
+	err error
+}
+
+// start launches the single background goroutine that drives r.r, feeding
+// every chunk it reads (copied out of its own scratch buffer) to ch. It exits
+// after the first error (including io.EOF), closing ch so Read can tell a
+// closed, drained channel apart from one that still has a pending result.
+func (r *deadlineReader) start() {
+	r.ch = make(chan readResult, 1)
+	go func() {
+		scratch := make([]byte, 32*1024)
+		for {
+			n, err := r.r.Read(scratch)
+			var chunk []byte
+			if n > 0 {
+				chunk = make([]byte, n)
+				copy(chunk, scratch[:n])
+			}
+			r.ch <- readResult{chunk, err}
+			if err != nil {
+				close(r.ch)
+				return
+			}
+		}
+	}()
+}
+
+func (r *deadlineReader) Read(p []byte) (int, error) {
+	r.once.Do(r.start)
+
+	if len(r.buf) > 0 {
+		n := copy(p, r.buf)
+		r.buf = r.buf[n:]
+		return n, nil
+	}
+
+	select {
+	case res, ok := <-r.ch:
+		if !ok {
+			return 0, io.EOF
+		}
+		n := copy(p, res.b)
+		if n < len(res.b) {
+			r.buf = res.b[n:]
+		}
+		return n, res.err
+	case <-r.dl.Done():
+		return 0, ErrTimeout
+	}
+}
@@ -0,0 +1,187 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package transport
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+
+	"github.com/linuxboot/contest/pkg/test"
+	"github.com/linuxboot/contest/pkg/xcontext"
+
+	"github.com/insomniacslk/xjson"
+)
+
+// defaultKillGrace is how long Wait gives the remote process to exit after
+// SIGTERM before escalating to SIGKILL, when the step doesn't override it.
+const defaultKillGrace = 5 * time.Second
+
+// sshOptions configures the "ssh" transport. It is unmarshalled from the raw
+// Parameters.Options of the step, then expanded through the target's
+// ParamExpander, so fields may contain "${...}" references.
+type sshOptions struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password,omitempty"`
+	KeyPath  string `json:"key_path,omitempty"`
+	// KillGrace overrides defaultKillGrace.
+	KillGrace xjson.Duration `json:"kill_grace,omitempty"`
+}
+
+// sshTransport creates processes over SSH, dialing a fresh connection for
+// each one.
+type sshTransport struct {
+	opts sshOptions
+}
+
+func newSSHTransport(options json.RawMessage, pe *test.ParamExpander) (Transport, error) {
+	var raw sshOptions
+	if err := json.Unmarshal(options, &raw); err != nil {
+		return nil, fmt.Errorf("invalid ssh transport options: %w", err)
+	}
+	var opts sshOptions
+	if err := pe.ExpandObject(raw, &opts); err != nil {
+		return nil, fmt.Errorf("failed to expand ssh transport options: %w", err)
+	}
+	if opts.Host == "" {
+		return nil, fmt.Errorf("ssh transport requires a host")
+	}
+	if opts.Port == 0 {
+		opts.Port = 22
+	}
+	return &sshTransport{opts: opts}, nil
+}
+
+func (t *sshTransport) dial() (*ssh.Client, error) {
+	var auth []ssh.AuthMethod
+	if t.opts.KeyPath != "" {
+		key, err := ioutil.ReadFile(t.opts.KeyPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot read private key %q: %w", t.opts.KeyPath, err)
+		}
+		signer, err := ssh.ParsePrivateKey(key)
+		if err != nil {
+			return nil, fmt.Errorf("cannot parse private key %q: %w", t.opts.KeyPath, err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if t.opts.Password != "" {
+		auth = append(auth, ssh.Password(t.opts.Password))
+	}
+
+	config := &ssh.ClientConfig{
+		User:            t.opts.User,
+		Auth:            auth,
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+		Timeout:         10 * time.Second,
+	}
+	return ssh.Dial("tcp", fmt.Sprintf("%s:%d", t.opts.Host, t.opts.Port), config)
+}
+
+// NewProcess implements Transport.
+func (t *sshTransport) NewProcess(ctx xcontext.Context, privileged string, args []string) (Process, error) {
+	client, err := t.dial()
+	if err != nil {
+		return nil, fmt.Errorf("failed to dial %s@%s:%d: %w", t.opts.User, t.opts.Host, t.opts.Port, err)
+	}
+	session, err := client.NewSession()
+	if err != nil {
+		client.Close()
+		return nil, fmt.Errorf("failed to open ssh session: %w", err)
+	}
+
+	killGrace := time.Duration(t.opts.KillGrace)
+	if killGrace <= 0 {
+		killGrace = defaultKillGrace
+	}
+
+	return &sshProcess{
+		client:    client,
+		session:   session,
+		cmd:       strings.Join(append([]string{privileged}, args...), " "),
+		killGrace: killGrace,
+		readDL:    newDeadlineTimer(),
+		writeDL:   newDeadlineTimer(),
+	}, nil
+}
+
+// sshProcess is a single command run over one SSH session. It implements
+// Process.
+type sshProcess struct {
+	client  *ssh.Client
+	session *ssh.Session
+	cmd     string
+
+	killGrace time.Duration
+	readDL    *deadlineTimer
+	writeDL   *deadlineTimer
+}
+
+func (p *sshProcess) StdoutPipe() (io.Reader, error) {
+	r, err := p.session.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	return &deadlineReader{r: r, dl: p.readDL}, nil
+}
+
+func (p *sshProcess) StderrPipe() (io.Reader, error) {
+	r, err := p.session.StderrPipe()
+	if err != nil {
+		return nil, err
+	}
+	return &deadlineReader{r: r, dl: p.readDL}, nil
+}
+
+func (p *sshProcess) Start(ctx xcontext.Context) error {
+	return p.session.Start(p.cmd)
+}
+
+// Wait blocks on the remote command's exit. If ctx is done before the
+// command exits on its own, Wait sends SIGTERM, gives the process
+// p.killGrace to act on it, then escalates to SIGKILL, and returns
+// ErrTimeout: that's how callers tell "we killed it" apart from whatever
+// exit status the tool itself would have returned.
+func (p *sshProcess) Wait(ctx xcontext.Context) error {
+	defer p.client.Close()
+
+	done := make(chan error, 1)
+	go func() { done <- p.session.Wait() }()
+
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		_ = p.session.Signal(ssh.SIGTERM)
+		select {
+		case <-done:
+		case <-time.After(p.killGrace):
+			_ = p.session.Signal(ssh.SIGKILL)
+			// dropping the connection unblocks session.Wait even if the
+			// remote side never acts on SIGKILL (e.g. a hung DUT).
+			_ = p.client.Close()
+			<-done
+		}
+		return fmt.Errorf("%w: %v", ErrTimeout, ctx.Err())
+	}
+}
+
+func (p *sshProcess) SetReadDeadline(t time.Time) error {
+	p.readDL.set(t)
+	return nil
+}
+
+func (p *sshProcess) SetWriteDeadline(t time.Time) error {
+	p.writeDL.set(t)
+	return nil
+}
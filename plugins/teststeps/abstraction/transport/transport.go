@@ -0,0 +1,80 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+// Package transport abstracts over how a teststep plugin runs a command on
+// a target: today only "ssh" is implemented, but the Transport/Process
+// interfaces let a plugin stay protocol-agnostic.
+package transport
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/linuxboot/contest/pkg/test"
+	"github.com/linuxboot/contest/pkg/xcontext"
+)
+
+// Keyword is the TestStepParameter key under which transport parameters
+// (Parameters, below) are expected.
+const Keyword = "transport"
+
+// ErrTimeout is returned by Process.Wait (and surfaced through deadline-aware
+// reads/writes) when the context's deadline fires before the remote command
+// finished on its own. Callers distinguish "we killed it" from "the tool
+// failed" with errors.Is(err, ErrTimeout).
+var ErrTimeout = errors.New("transport: deadline exceeded while waiting for process")
+
+// Parameters is the user-facing description of which transport to use and
+// how to reach the target, e.g. {"proto": "ssh", "options": {"host": ...}}.
+type Parameters struct {
+	Proto   string          `json:"proto"`
+	Options json.RawMessage `json:"options"`
+}
+
+// Transport creates remote processes on a target.
+type Transport interface {
+	NewProcess(ctx xcontext.Context, privileged string, args []string) (Process, error)
+}
+
+// Process is a single command, created but not necessarily started yet, on
+// a target reached through a Transport.
+type Process interface {
+	// StdoutPipe and StderrPipe return readers for the process's output.
+	// They must be called before Start.
+	StdoutPipe() (io.Reader, error)
+	StderrPipe() (io.Reader, error)
+
+	// Start launches the process. A non-nil error here means the process
+	// never ran at all (as opposed to Wait's error, which describes how a
+	// launched process ended).
+	Start(ctx xcontext.Context) error
+
+	// Wait blocks until the process exits or ctx is done, whichever comes
+	// first. If ctx's deadline fires first, Wait sends SIGTERM to the
+	// remote process, gives it a grace period to exit, then sends SIGKILL,
+	// and returns an error satisfying errors.Is(err, ErrTimeout).
+	Wait(ctx xcontext.Context) error
+
+	// SetReadDeadline and SetWriteDeadline bound how long a single
+	// read/write on the process's pipes may block, independent of the
+	// overall Wait deadline above. A zero time.Time clears the deadline.
+	SetReadDeadline(t time.Time) error
+	SetWriteDeadline(t time.Time) error
+}
+
+// NewTransport constructs the Transport named by proto, configured by
+// options (still containing unexpanded parameter expressions) expanded
+// through pe.
+func NewTransport(proto string, options json.RawMessage, pe *test.ParamExpander) (Transport, error) {
+	switch proto {
+	case "ssh":
+		return newSSHTransport(options, pe)
+	default:
+		return nil, fmt.Errorf("unsupported transport protocol %q", proto)
+	}
+}
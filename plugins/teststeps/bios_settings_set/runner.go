@@ -1,16 +1,18 @@
 package bios_settings_set
 
 import (
-	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"sync"
 	"time"
 
+	"github.com/linuxboot/contest/pkg/event"
 	"github.com/linuxboot/contest/pkg/event/testevent"
 	"github.com/linuxboot/contest/pkg/target"
 	"github.com/linuxboot/contest/pkg/test"
 	"github.com/linuxboot/contest/pkg/xcontext"
+	"github.com/linuxboot/contest/plugins/teststeps/abstraction/capture"
 	"github.com/linuxboot/contest/plugins/teststeps/abstraction/transport"
 )
 
@@ -27,6 +29,36 @@ type Error struct {
 	Msg string `json:"error"`
 }
 
+// events that we may emit during the plugin's lifecycle.
+const (
+	EventStdout = event.Name("Stdout")
+	EventStderr = event.Name("Stderr")
+)
+
+type eventPayload struct {
+	Msg string
+}
+
+func emitEvent(ctx xcontext.Context, name event.Name, payload interface{}, tgt *target.Target, ev testevent.Emitter) error {
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal payload for event '%s': %w", name, err)
+	}
+
+	msg := json.RawMessage(payloadData)
+	data := testevent.Data{
+		EventName: name,
+		Target:    tgt,
+		Payload:   &msg,
+	}
+
+	if err := ev.Emit(ctx, data); err != nil {
+		return fmt.Errorf("cannot emit event '%s': %w", name, err)
+	}
+
+	return nil
+}
+
 type TargetRunner struct {
 	ts *TestStep
 	ev testevent.Emitter
@@ -112,6 +144,13 @@ func (r *TargetRunner) runSet(
 		return nil, fmt.Errorf("failed to create process: %v", err)
 	}
 
+	// bound the streaming reads by the same deadline Wait uses to kill the
+	// remote process, so a reader that never sees EOF (e.g. a hung tool that
+	// nonetheless keeps the connection open) doesn't block forever either.
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = proc.SetReadDeadline(deadline)
+	}
+
 	stdoutPipe, err := proc.StdoutPipe()
 	if err != nil {
 		return nil, fmt.Errorf("failed to pipe stdout: %v", err)
@@ -122,6 +161,31 @@ func (r *TargetRunner) runSet(
 		return nil, fmt.Errorf("failed to pipe stderr: %v", err)
 	}
 
+	stdoutCap := capture.New(
+		func(ctx xcontext.Context, chunk string) error {
+			return emitEvent(ctx, EventStdout, eventPayload{Msg: chunk}, target, r.ev)
+		},
+		captureOptions(r.ts.Options),
+	)
+	stderrCap := capture.New(
+		func(ctx xcontext.Context, chunk string) error {
+			return emitEvent(ctx, EventStderr, eventPayload{Msg: chunk}, target, r.ev)
+		},
+		captureOptions(r.ts.Options),
+	)
+
+	var wg sync.WaitGroup
+	var stdoutErr, stderrErr error
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stdoutErr = stdoutCap.Run(ctx, stdoutPipe)
+	}()
+	go func() {
+		defer wg.Done()
+		stderrErr = stderrCap.Run(ctx, stderrPipe)
+	}()
+
 	// try to start the process, if that succeeds then the outcome is the result of
 	// waiting on the process for its result; this way there's a semantic difference
 	// between "an error occured while launching" and "this was the outcome of the execution"
@@ -130,48 +194,45 @@ func (r *TargetRunner) runSet(
 		outcome = proc.Wait(ctx)
 	}
 
-	stdout, stderr := getOutputFromReader(stdoutPipe, stderrPipe)
-
-	if err := parseSetOutput(stderr, params.Parameter.ShallFail); err != nil {
-		return nil, err
-	}
-
-	if err := emitEvent(ctx, EventStdout, eventPayload{Msg: string(stdout)}, target, r.ev); err != nil {
-		return nil, fmt.Errorf("cannot emit event: %v", err)
-	}
-	if err := emitEvent(ctx, EventStderr, eventPayload{Msg: string(stderr)}, target, r.ev); err != nil {
-		return nil, fmt.Errorf("cannot emit event: %v", err)
+	// the process is done (or was killed), so both pipes are now at EOF: wait for the
+	// streaming goroutines to drain and emit whatever they have left.
+	wg.Wait()
+
+	// errors.Is(outcome, transport.ErrTimeout) means the context deadline fired and
+	// the transport killed the remote process (SIGTERM then SIGKILL) before it had a
+	// chance to finish on its own: that's "we killed it", not a verdict from the tool
+	// itself, so surface it as a step error rather than as the target's outcome. Check
+	// this before the streaming errors below: the same cancellation that produced it
+	// also unblocks the capture goroutines via ctx.Done(), so their ctx.Err() isn't a
+	// streaming failure in its own right and shouldn't shadow this one.
+	if errors.Is(outcome, transport.ErrTimeout) {
+		return nil, fmt.Errorf("timed out waiting for %q, DUT process was killed: %w", cmd, outcome)
 	}
 
-	return outcome, nil
-}
-
-// getOutputFromReader reads data from the provided io.Reader instances
-// representing stdout and stderr, and returns the collected output as byte slices.
-func getOutputFromReader(stdout, stderr io.Reader) ([]byte, []byte) {
-	// Read from the stdout and stderr pipe readers
-	outBuffer, err := readBuffer(stdout)
-	if err != nil {
-		fmt.Printf("failed to read from Stdout buffer: %v\n", err)
+	if ctx.Err() == nil {
+		if stdoutErr != nil {
+			return nil, fmt.Errorf("failed to stream stdout: %w", stdoutErr)
+		}
+		if stderrErr != nil {
+			return nil, fmt.Errorf("failed to stream stderr: %w", stderrErr)
+		}
 	}
 
-	errBuffer, err := readBuffer(stderr)
-	if err != nil {
-		fmt.Printf("failed to read from Stderr buffer: %v\n", err)
+	if err := parseSetOutput(stderrCap.Bytes(), params.Parameter.ShallFail); err != nil {
+		return nil, err
 	}
 
-	return outBuffer, errBuffer
+	return outcome, nil
 }
 
-// readBuffer reads data from the provided io.Reader and returns it as a byte slice.
-// It dynamically accumulates the data using a bytes.Buffer.
-func readBuffer(r io.Reader) ([]byte, error) {
-	buf := &bytes.Buffer{}
-	_, err := io.Copy(buf, r)
-	if err != nil && err != io.EOF {
-		return nil, err
+// captureOptions translates the step's Options into capture.Options,
+// leaving fields at zero (package defaults) when left unset.
+func captureOptions(opts Options) capture.Options {
+	return capture.Options{
+		MaxLineSize:      opts.MaxLineSize,
+		FlushInterval:    time.Duration(opts.FlushInterval),
+		MaxCapturedBytes: opts.MaxCapturedBytes,
 	}
-	return buf.Bytes(), nil
 }
 
 func parseSetOutput(stderr []byte, fail bool) error {
@@ -192,4 +253,4 @@ func parseSetOutput(stderr []byte, fail bool) error {
 	}
 
 	return nil
-}
\ No newline at end of file
+}
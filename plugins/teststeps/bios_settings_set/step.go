@@ -0,0 +1,120 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package bios_settings_set
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/linuxboot/contest/pkg/event"
+	"github.com/linuxboot/contest/pkg/event/testevent"
+	"github.com/linuxboot/contest/pkg/events"
+	"github.com/linuxboot/contest/pkg/test"
+	"github.com/linuxboot/contest/pkg/xcontext"
+	"github.com/linuxboot/contest/plugins/teststeps"
+	"github.com/linuxboot/contest/plugins/teststeps/abstraction/transport"
+
+	"github.com/insomniacslk/xjson"
+)
+
+// Name is the name used to look this plugin up.
+var Name = "BiosSettingsSet"
+
+// Options controls execution of the step, independent of any one target.
+type Options struct {
+	// Timeout bounds how long the remote command is allowed to run before
+	// its transport is cancelled and the process killed.
+	Timeout xjson.Duration `json:"timeout,omitempty"`
+
+	// MaxLineSize, FlushInterval and MaxCapturedBytes tune the stdout/stderr
+	// streaming capture, mirroring hwaas.Options: defaults from the capture
+	// package's own constants apply when left zero.
+	MaxLineSize      int            `json:"max_line_size,omitempty"`
+	FlushInterval    xjson.Duration `json:"flush_interval,omitempty"`
+	MaxCapturedBytes int            `json:"max_captured_bytes,omitempty"`
+}
+
+type transportParams struct {
+	Proto   string          `json:"proto"`
+	Options json.RawMessage `json:"options"`
+}
+
+type parameterParams struct {
+	ToolPath  string `json:"tool_path"`
+	Option    string `json:"option"`
+	Value     string `json:"value"`
+	Password  string `json:"password,omitempty"`
+	KeyPath   string `json:"key_path,omitempty"`
+	ShallFail bool   `json:"shall_fail,omitempty"`
+}
+
+// inputStepParams is the (possibly still-templated) set of parameters a
+// target expands into before TargetRunner.Run acts on them.
+type inputStepParams struct {
+	Transport transportParams `json:"transport"`
+	Parameter parameterParams `json:"parameter"`
+}
+
+// TestStep implementation for this teststep plugin.
+type TestStep struct {
+	inputStepParams inputStepParams
+	Options         Options
+}
+
+// Run executes the step.
+func (ts *TestStep) Run(ctx xcontext.Context, ch test.TestStepChannels, params test.TestStepParameters, ev testevent.Emitter, resumeState json.RawMessage) (json.RawMessage, error) {
+	tr := NewTargetRunner(ts, ev)
+	return teststeps.ForEachTarget(Name, ctx, ch, tr.Run)
+}
+
+func (ts *TestStep) populateParams(stepParams test.TestStepParameters) error {
+	transportParam := stepParams.GetOne(transport.Keyword)
+	if transportParam.IsEmpty() {
+		return fmt.Errorf("transport cannot be empty")
+	}
+	if err := json.Unmarshal(transportParam.JSON(), &ts.inputStepParams.Transport); err != nil {
+		return fmt.Errorf("failed to deserialize transport: %v", err)
+	}
+
+	parameterParam := stepParams.GetOne("parameter")
+	if parameterParam.IsEmpty() {
+		return fmt.Errorf("parameter cannot be empty")
+	}
+	if err := json.Unmarshal(parameterParam.JSON(), &ts.inputStepParams.Parameter); err != nil {
+		return fmt.Errorf("failed to deserialize parameter: %v", err)
+	}
+
+	var opts Options
+	if optsParam := stepParams.GetOne("options"); !optsParam.IsEmpty() {
+		if err := json.Unmarshal(optsParam.JSON(), &opts); err != nil {
+			return fmt.Errorf("failed to deserialize options: %v", err)
+		}
+	}
+	ts.Options = opts
+
+	return nil
+}
+
+// ValidateParameters validates the parameters associated to the TestStep.
+func (ts *TestStep) ValidateParameters(ctx xcontext.Context, params test.TestStepParameters) error {
+	return ts.populateParams(params)
+}
+
+// New initializes and returns a new test step.
+func New() test.TestStep {
+	return &TestStep{}
+}
+
+// Load returns the name, factory and events which are needed to register
+// the step.
+func Load() (string, test.TestStepFactory, []event.Name) {
+	return Name, New, events.Events
+}
+
+// Name returns the name of the Step.
+func (ts TestStep) Name() string {
+	return Name
+}
@@ -0,0 +1,156 @@
+package hsi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/linuxboot/contest/pkg/event"
+	"github.com/linuxboot/contest/pkg/event/testevent"
+	"github.com/linuxboot/contest/pkg/target"
+	"github.com/linuxboot/contest/pkg/test"
+	"github.com/linuxboot/contest/pkg/xcontext"
+	"github.com/linuxboot/contest/plugins/teststeps/abstraction/transport"
+)
+
+const (
+	supportedProto = "ssh"
+	privileged     = "sudo"
+	toolPath       = "hsi"
+)
+
+// events that we may emit during the plugin's lifecycle.
+const (
+	EventStdout = event.Name("Stdout")
+	EventStderr = event.Name("Stderr")
+)
+
+type eventPayload struct {
+	Msg string
+}
+
+func emitEvent(ctx xcontext.Context, name event.Name, payload interface{}, tgt *target.Target, ev testevent.Emitter) error {
+	payloadData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("cannot marshal payload for event '%s': %w", name, err)
+	}
+
+	msg := json.RawMessage(payloadData)
+	data := testevent.Data{
+		EventName: name,
+		Target:    tgt,
+		Payload:   &msg,
+	}
+
+	if err := ev.Emit(ctx, data); err != nil {
+		return fmt.Errorf("cannot emit event '%s': %w", name, err)
+	}
+
+	return nil
+}
+
+type TargetRunner struct {
+	ts *TestStep
+	ev testevent.Emitter
+}
+
+func NewTargetRunner(ts *TestStep, ev testevent.Emitter) *TargetRunner {
+	return &TargetRunner{
+		ts: ts,
+		ev: ev,
+	}
+}
+
+// Run queries the HSI status of target over the configured transport. It is
+// a read-only operation: on a timeout there is nothing to distinguish
+// beyond "the DUT process was killed", since there's no tool-reported
+// outcome to compare it against.
+func (r *TargetRunner) Run(ctx xcontext.Context, target *target.Target) error {
+	ctx.Infof("Querying HSI status on target %s", target)
+
+	if r.ts.transport.Proto != supportedProto {
+		return fmt.Errorf("only %q is supported as protocol in this teststep", supportedProto)
+	}
+
+	pe := test.NewParamExpander(target)
+	tr, err := transport.NewTransport(r.ts.transport.Proto, r.ts.transport.Options, pe)
+	if err != nil {
+		return fmt.Errorf("failed to create transport: %w", err)
+	}
+
+	proc, err := tr.NewProcess(ctx, privileged, []string{toolPath, "status", "--json"})
+	if err != nil {
+		return fmt.Errorf("failed to create process: %w", err)
+	}
+
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = proc.SetReadDeadline(deadline)
+	}
+
+	stdoutPipe, err := proc.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("failed to pipe stdout: %w", err)
+	}
+	stderrPipe, err := proc.StderrPipe()
+	if err != nil {
+		return fmt.Errorf("failed to pipe stderr: %w", err)
+	}
+
+	// The pipes must be drained concurrently with (not after) Wait: Wait
+	// closes the underlying SSH client on every return, including the
+	// normal exit path, and per x/crypto/ssh's contract reads must complete
+	// before the session is closed or their data is lost. Start the reads
+	// before Start so neither pipe can fill up and block the remote command
+	// before we get around to reading it.
+	var stdout, stderr []byte
+	var stdoutErr, stderrErr error
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stdout, stdoutErr = io.ReadAll(stdoutPipe)
+	}()
+	go func() {
+		defer wg.Done()
+		stderr, stderrErr = io.ReadAll(stderrPipe)
+	}()
+
+	// as in bios_settings_set, a nil Start error means the outcome is whatever
+	// Wait reports, while a non-nil Start error means the process never ran.
+	outcome := proc.Start(ctx)
+	if outcome == nil {
+		outcome = proc.Wait(ctx)
+	}
+
+	wg.Wait()
+
+	if err := emitEvent(ctx, EventStdout, eventPayload{Msg: string(stdout)}, target, r.ev); err != nil {
+		return fmt.Errorf("cannot emit event: %w", err)
+	}
+	if err := emitEvent(ctx, EventStderr, eventPayload{Msg: string(stderr)}, target, r.ev); err != nil {
+		return fmt.Errorf("cannot emit event: %w", err)
+	}
+
+	// errors.Is(outcome, transport.ErrTimeout): the context deadline fired and the
+	// transport killed the remote process before it reported its own result, so
+	// surface it distinctly rather than as a tool-reported failure. Check this
+	// before the stream-read errors below: the same cancellation that produced
+	// it also unblocks the deadline-bound pipes with transport.ErrTimeout, which
+	// isn't a streaming failure in its own right and shouldn't shadow this one.
+	if errors.Is(outcome, transport.ErrTimeout) {
+		return fmt.Errorf("timed out waiting for hsi status, DUT process was killed: %w", outcome)
+	}
+
+	if ctx.Err() == nil {
+		if stdoutErr != nil {
+			return fmt.Errorf("failed to read stdout: %w", stdoutErr)
+		}
+		if stderrErr != nil {
+			return fmt.Errorf("failed to read stderr: %w", stderrErr)
+		}
+	}
+
+	return outcome
+}
@@ -0,0 +1,108 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package hwaas
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/linuxboot/contest/pkg/event"
+	"github.com/linuxboot/contest/pkg/event/testevent"
+	"github.com/linuxboot/contest/pkg/test"
+	"github.com/linuxboot/contest/pkg/xcontext"
+	"github.com/linuxboot/contest/plugins/teststeps"
+
+	"github.com/insomniacslk/xjson"
+)
+
+// Name is the name used to look this plugin up.
+var Name = "HWaaS"
+
+const (
+	defaultPort    = 8080
+	defaultTimeout = 2 * time.Minute
+)
+
+// Parameter is the user-facing description of what to run on the DUT and
+// where.
+type Parameter struct {
+	Host    string   `json:"host"`
+	Port    int      `json:"port,omitempty"`
+	Command string   `json:"command"`
+	Args    []string `json:"args,omitempty"`
+}
+
+// Options controls execution of the step, independent of any one target.
+type Options struct {
+	Timeout xjson.Duration `json:"timeout,omitempty"`
+
+	// MaxLineSize, FlushInterval and MaxCapturedBytes tune the stdout/stderr
+	// streaming capture, mirroring bios_settings_set.Options: defaults from
+	// the capture package's own constants apply when left zero.
+	MaxLineSize      int            `json:"max_line_size,omitempty"`
+	FlushInterval    xjson.Duration `json:"flush_interval,omitempty"`
+	MaxCapturedBytes int            `json:"max_captured_bytes,omitempty"`
+}
+
+// TestStep implementation for this teststep plugin.
+type TestStep struct {
+	Parameter Parameter
+	Options   Options
+}
+
+// Run executes the step.
+func (ts *TestStep) Run(ctx xcontext.Context, ch test.TestStepChannels, params test.TestStepParameters, ev testevent.Emitter, resumeState json.RawMessage) (json.RawMessage, error) {
+	tr := NewTargetRunner(ts, ev)
+	return teststeps.ForEachTarget(Name, ctx, ch, tr.Run)
+}
+
+func (ts *TestStep) populateParams(stepParams test.TestStepParameters) error {
+	paramParam := stepParams.GetOne("parameter")
+	if paramParam.IsEmpty() {
+		return fmt.Errorf("parameter cannot be empty")
+	}
+	if err := json.Unmarshal(paramParam.JSON(), &ts.Parameter); err != nil {
+		return fmt.Errorf("failed to deserialize parameter: %v", err)
+	}
+	if ts.Parameter.Port == 0 {
+		ts.Parameter.Port = defaultPort
+	}
+
+	var opts Options
+	if optsParam := stepParams.GetOne("options"); !optsParam.IsEmpty() {
+		if err := json.Unmarshal(optsParam.JSON(), &opts); err != nil {
+			return fmt.Errorf("failed to deserialize options: %v", err)
+		}
+	}
+	if opts.Timeout == 0 {
+		opts.Timeout = xjson.Duration(defaultTimeout)
+	}
+	ts.Options = opts
+
+	return nil
+}
+
+// ValidateParameters validates the parameters associated to the TestStep.
+func (ts *TestStep) ValidateParameters(ctx xcontext.Context, params test.TestStepParameters) error {
+	return ts.populateParams(params)
+}
+
+// New initializes and returns a new test step.
+func New() test.TestStep {
+	return &TestStep{}
+}
+
+// Load returns the name, factory and events which are needed to register
+// the step.
+func Load() (string, test.TestStepFactory, []event.Name) {
+	return Name, New, Events
+}
+
+// Name returns the name of the Step.
+func (ts TestStep) Name() string {
+	return Name
+}
@@ -0,0 +1,114 @@
+// Copyright (c) Facebook, Inc. and its affiliates.
+//
+// This source code is licensed under the MIT license found in the
+// LICENSE file in the root directory of this source tree.
+
+package hwaas
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/linuxboot/contest/pkg/event/testevent"
+	"github.com/linuxboot/contest/pkg/target"
+	"github.com/linuxboot/contest/pkg/test"
+	"github.com/linuxboot/contest/pkg/xcontext"
+	"github.com/linuxboot/contest/plugins/teststeps/abstraction/capture"
+)
+
+type TargetRunner struct {
+	ts *TestStep
+	ev testevent.Emitter
+}
+
+func NewTargetRunner(ts *TestStep, ev testevent.Emitter) *TargetRunner {
+	return &TargetRunner{
+		ts: ts,
+		ev: ev,
+	}
+}
+
+// Run triggers the configured hwaas command against target's agent and
+// streams its response body as it arrives, emitting EventStdout chunks (or
+// EventStderr, for any status other than 200 OK) instead of buffering the whole body
+// before emitting anything.
+func (r *TargetRunner) Run(ctx xcontext.Context, tgt *target.Target) error {
+	ctx.Infof("Executing on target %s", tgt)
+
+	timeout := time.Duration(r.ts.Options.Timeout)
+	if timeout != 0 {
+		var cancel xcontext.CancelFunc
+		ctx, cancel = xcontext.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	pe := test.NewParamExpander(tgt)
+	var param Parameter
+	if err := pe.ExpandObject(r.ts.Parameter, &param); err != nil {
+		return err
+	}
+
+	var report strings.Builder
+	writeTestStep(&report, r.ts)
+	writeCommand(&report, param.Command, param.Args...)
+
+	reqBody, err := json.Marshal(struct {
+		Args []string `json:"args"`
+	}{Args: param.Args})
+	if err != nil {
+		return fmt.Errorf("failed to build request body: %w", err)
+	}
+
+	url := fmt.Sprintf("http://%s:%d/%s", param.Host, param.Port, param.Command)
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(reqBody))
+	if err != nil {
+		return fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		// ctx.Err() set means the request was aborted by our own deadline,
+		// not by a remote failure: that's "we killed it", not the tool's
+		// verdict, so report it distinctly.
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out waiting for hwaas command %q, request was cancelled: %w", param.Command, ctx.Err())
+		}
+		return fmt.Errorf("hwaas request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	eventName := EventStdout
+	if resp.StatusCode != http.StatusOK {
+		eventName = EventStderr
+	}
+
+	respCap := capture.New(
+		func(ctx xcontext.Context, chunk string) error {
+			return emitEvent(ctx, eventName, eventPayload{Msg: chunk}, tgt, r.ev)
+		},
+		capture.Options{
+			MaxLineSize:      r.ts.Options.MaxLineSize,
+			FlushInterval:    time.Duration(r.ts.Options.FlushInterval),
+			MaxCapturedBytes: r.ts.Options.MaxCapturedBytes,
+		},
+	)
+	if err := respCap.Run(ctx, resp.Body); err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("timed out waiting for hwaas command %q, request was cancelled: %w", param.Command, ctx.Err())
+		}
+		return fmt.Errorf("failed to stream response: %w", err)
+	}
+
+	writeCommandOutput(&report, string(respCap.Bytes()))
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("hwaas command %q failed with status %s:\n%s", param.Command, resp.Status, report.String())
+	}
+
+	return nil
+}